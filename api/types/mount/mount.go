@@ -2,6 +2,10 @@ package mount
 
 import (
 	"os"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/docker/docker/api/types/registry"
 )
 
 // Type represents the type of a mount.
@@ -93,6 +97,28 @@ type BindOptions struct {
 	ReadOnlyNonRecursive bool `json:",omitempty"`
 	// ReadOnlyForceRecursive raises an error if the mount cannot be made recursively read-only.
 	ReadOnlyForceRecursive bool `json:",omitempty"`
+	// IDMapping describes a user namespace ID mapping for the bind mount.
+	//
+	// Nothing reads this field: setting it applies no ID mapping and
+	// returns no error. There is no REST API or CLI flag for it.
+	IDMapping *IDMapping `json:",omitempty"`
+}
+
+// IDMapping describes a uid/gid mapping for a bind mount, in the form
+// used by mount_setattr(MOUNT_ATTR_IDMAP). IDs not covered by one of the ranges in
+// UIDMaps/GIDMaps are left unmapped.
+type IDMapping struct {
+	UIDMaps []IDMap `json:",omitempty"`
+	GIDMaps []IDMap `json:",omitempty"`
+}
+
+// IDMap represents a single line in /proc/<pid>/{uid,gid}_map, mapping a
+// contiguous range of Size IDs starting at ContainerID inside the
+// container's user namespace to the range starting at HostID outside it.
+type IDMap struct {
+	ContainerID uint32 `json:",omitempty"`
+	HostID      uint32 `json:",omitempty"`
+	Size        uint32 `json:",omitempty"`
 }
 
 // VolumeOptions represents the options for a mount of type volume.
@@ -105,6 +131,37 @@ type VolumeOptions struct {
 
 type ImageOptions struct {
 	Subpath string `json:",omitempty"`
+	// Pull describes how to pull the image identified by a registry
+	// reference when it is not present locally.
+	//
+	// Nothing reads this field: an image that is not present locally
+	// fails to mount and is not pulled, whether or not Pull is set.
+	Pull *ImagePullOptions `json:",omitempty"`
+}
+
+// ImagePullOptions lists options for pulling the image backing an
+// ImageOptions mount. Nothing reads them (see ImageOptions.Pull), so
+// none of the behaviour described for the fields below takes place.
+type ImagePullOptions struct {
+	// Reference is the image reference to resolve and, if necessary,
+	// pull, e.g. "docker.io/library/alpine:3.19".
+	Reference string `json:",omitempty"`
+	// Auth supplies registry credentials for Reference. If nil, the
+	// daemon falls back to its configured ~/.docker/config.json-style
+	// auth for the reference's registry.
+	Auth *registry.AuthConfig `json:",omitempty"`
+	// Platform restricts the pull to a single platform of a multi-platform
+	// image. If nil, the daemon's default platform matching applies.
+	Platform *ocispec.Platform `json:",omitempty"`
+	// AlwaysPull forces a pull even if Reference already resolves to an
+	// image present locally.
+	AlwaysPull bool `json:",omitempty"`
+	// Insecure allows pulling from registries without verified TLS or over
+	// plain HTTP.
+	Insecure bool `json:",omitempty"`
+	// Writable allows copy-up writes into the mount. Pulled images, like
+	// local ones, are mounted read-only unless Writable is set.
+	Writable bool `json:",omitempty"`
 }
 
 // Driver represents a volume driver.
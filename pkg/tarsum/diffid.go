@@ -0,0 +1,208 @@
+package tarsum
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+
+	digest "github.com/opencontainers/go-digest"
+)
+
+// DiffIDHasher is an io.Writer that computes the OCI DiffID (the sha256
+// digest of an uncompressed tar stream) of the bytes written to it.
+type DiffIDHasher interface {
+	io.Writer
+	// Digest returns the DiffID of the bytes written so far.
+	Digest() digest.Digest
+}
+
+type diffIDHasher struct {
+	h   hash.Hash
+	tee io.Writer
+}
+
+// NewDiffIDWriter returns a DiffIDHasher that computes the OCI DiffID of
+// the uncompressed tar stream written to it. If w is non-nil, every
+// written byte is also copied to w, so callers can tee the same stream
+// into a TarSum calculation (e.g. via [NewVerifier]) without a second
+// pass over the layer.
+func NewDiffIDWriter(w io.Writer) (DiffIDHasher, error) {
+	return &diffIDHasher{h: sha256.New(), tee: w}, nil
+}
+
+func (d *diffIDHasher) Write(p []byte) (int, error) {
+	n, err := d.h.Write(p)
+	if err != nil {
+		return n, err
+	}
+	if d.tee != nil {
+		if _, err := d.tee.Write(p[:n]); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func (d *diffIDHasher) Digest() digest.Digest {
+	return digest.NewDigest(digest.SHA256, d.h)
+}
+
+// DiffIDFromTar computes the OCI DiffID of r, an uncompressed tar stream.
+func DiffIDFromTar(r io.Reader) (digest.Digest, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return digest.NewDigest(digest.SHA256, h), nil
+}
+
+// Verifier checks a written, uncompressed tar stream against an expected
+// digest.
+type Verifier interface {
+	io.Writer
+	// Verify reports whether the bytes written so far hash to the
+	// expected digest.
+	Verify() error
+}
+
+// NewVerifier returns a Verifier for expected, which may be either a
+// TarSum checksum (e.g. "tarsum.v2+sha256:...") or a bare OCI DiffID
+// (e.g. "sha256:..."). It dispatches to the matching algorithm so callers
+// migrating between the two layer addressing schemes don't need to
+// branch on the digest format themselves.
+func NewVerifier(expected string) (Verifier, error) {
+	if v, err := GetVersionFromTarsum(expected); err == nil {
+		return newTarSumVerifier(v, expected), nil
+	}
+
+	dgst, err := digest.Parse(expected)
+	if err != nil {
+		return nil, fmt.Errorf("tarsum: %q is neither a TarSum checksum nor a valid digest: %w", expected, err)
+	}
+	return &diffIDVerifier{expected: dgst, h: sha256.New()}, nil
+}
+
+type diffIDVerifier struct {
+	expected digest.Digest
+	h        hash.Hash
+}
+
+func (dv *diffIDVerifier) Write(p []byte) (int, error) {
+	return dv.h.Write(p)
+}
+
+func (dv *diffIDVerifier) Verify() error {
+	got := digest.NewDigest(digest.SHA256, dv.h)
+	if got != dv.expected {
+		return fmt.Errorf("tarsum: digest mismatch: got %s, expected %s", got, dv.expected)
+	}
+	return nil
+}
+
+type tarSumResult struct {
+	sum string
+	err error
+}
+
+type tarSumVerifier struct {
+	version  Version
+	expected string
+	pw       *io.PipeWriter
+	done     chan tarSumResult
+}
+
+func newTarSumVerifier(v Version, expected string) *tarSumVerifier {
+	// VersionJSON's digest is defined by jcsDigestFromReader (per-entry
+	// canonical JSON, no content hashed), not by the key+value concatenation
+	// that tarSumHexFromReader computes for the other versions -- route to
+	// the matching function so a "tarsum.json+..." digest means the same
+	// thing here as it does via VerifyJCS.
+	digestFunc := jcsDigestFromReader
+	if v != VersionJSON {
+		digestFunc = func(r io.Reader) (string, error) { return tarSumHexFromReader(r, v) }
+	}
+
+	pr, pw := io.Pipe()
+	tv := &tarSumVerifier{version: v, expected: expected, pw: pw, done: make(chan tarSumResult, 1)}
+	go func() {
+		sum, err := digestFunc(pr)
+		if err != nil {
+			pr.CloseWithError(err)
+		}
+		tv.done <- tarSumResult{sum: sum, err: err}
+	}()
+	return tv
+}
+
+func (tv *tarSumVerifier) Write(p []byte) (int, error) {
+	return tv.pw.Write(p)
+}
+
+func (tv *tarSumVerifier) Verify() error {
+	if err := tv.pw.Close(); err != nil {
+		return err
+	}
+	result := <-tv.done
+	if result.err != nil {
+		return result.err
+	}
+	if got := tv.version.String() + "+sha256:" + result.sum; got != tv.expected {
+		return fmt.Errorf("tarsum: digest mismatch: got %s, expected %s", got, tv.expected)
+	}
+	return nil
+}
+
+// tarSumHexFromReader computes the hex-encoded TarSum payload (everything
+// after "{version}+sha256:") for version v of the uncompressed tar stream
+// read from r. Matching the algorithm used to produce every
+// "tarsum.v1+sha256:..."/"tarsum.v2+sha256:..." digest elsewhere in the
+// ecosystem, each entry is hashed independently (its selected header bytes
+// followed by its content), the resulting hex digests are sorted, and the
+// sorted, concatenated digests are hashed once more to produce the final
+// sum.
+func tarSumHexFromReader(r io.Reader, v Version) (string, error) {
+	selector, err := getTarHeaderSelector(v)
+	if err != nil {
+		return "", err
+	}
+
+	var sums []string
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		// Upstream's encodeHeader (github.com/docker/docker/pkg/tarsum)
+		// zeroes Uname/Gname before hashing, a historical quirk preserved
+		// for digest compatibility; match it here so real-world
+		// tarsum.v1/v2 digests for entries with owner names verify.
+		zeroed := *hdr
+		zeroed.Uname = ""
+		zeroed.Gname = ""
+
+		h := sha256.New()
+		for _, elem := range selector.selectHeaders(&zeroed) {
+			h.Write([]byte(elem[0] + elem[1]))
+		}
+		if _, err := io.Copy(h, tr); err != nil {
+			return "", err
+		}
+		sums = append(sums, hex.EncodeToString(h.Sum(nil)))
+	}
+	sort.Strings(sums)
+
+	final := sha256.New()
+	for _, sum := range sums {
+		final.Write([]byte(sum))
+	}
+	return hex.EncodeToString(final.Sum(nil)), nil
+}
@@ -0,0 +1,144 @@
+package tarsum
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"testing"
+)
+
+func buildTar(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range files {
+		hdr := &tar.Header{Name: name, Size: int64(len(content)), Mode: 0o644}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("WriteHeader: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDiffIDFromTarIsDeterministic(t *testing.T) {
+	data := buildTar(t, map[string]string{"foo": "hello world"})
+
+	d1, err := DiffIDFromTar(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("DiffIDFromTar: %v", err)
+	}
+	d2, err := DiffIDFromTar(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("DiffIDFromTar: %v", err)
+	}
+	if d1 != d2 {
+		t.Errorf("DiffIDFromTar not deterministic: %s != %s", d1, d2)
+	}
+	if err := d1.Validate(); err != nil {
+		t.Errorf("DiffIDFromTar returned invalid digest: %v", err)
+	}
+}
+
+func TestNewDiffIDWriterTeesToWriter(t *testing.T) {
+	data := buildTar(t, map[string]string{"foo": "hello world"})
+
+	var tee bytes.Buffer
+	dw, err := NewDiffIDWriter(&tee)
+	if err != nil {
+		t.Fatalf("NewDiffIDWriter: %v", err)
+	}
+	if _, err := io.Copy(dw, bytes.NewReader(data)); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+
+	want, err := DiffIDFromTar(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("DiffIDFromTar: %v", err)
+	}
+	if dw.Digest() != want {
+		t.Errorf("NewDiffIDWriter digest = %s, want %s", dw.Digest(), want)
+	}
+	if !bytes.Equal(tee.Bytes(), data) {
+		t.Error("NewDiffIDWriter did not tee all bytes to the provided writer")
+	}
+}
+
+func TestNewVerifierDispatch(t *testing.T) {
+	data := buildTar(t, map[string]string{"foo": "hello world"})
+
+	diffID, err := DiffIDFromTar(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("DiffIDFromTar: %v", err)
+	}
+
+	v, err := NewVerifier(diffID.String())
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	if _, err := v.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := v.Verify(); err != nil {
+		t.Errorf("Verify() with matching DiffID = %v, want nil", err)
+	}
+
+	bad, err := NewVerifier(diffID.String())
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	if _, err := bad.Write([]byte("not a tar")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := bad.Verify(); err == nil {
+		t.Error("Verify() with mismatching content = nil, want error")
+	}
+}
+
+func TestTarSumHexFromReaderIgnoresOwnerNames(t *testing.T) {
+	withNames := buildTarInOrder(t, [][2]string{{"foo", "hello world"}})
+	withoutNames := buildTarInOrder(t, [][2]string{{"foo", "hello world"}})
+
+	tr := tar.NewReader(bytes.NewReader(withNames))
+	hdr, err := tr.Next()
+	if err != nil {
+		t.Fatalf("tar.Next: %v", err)
+	}
+	hdr.Uname = "alice"
+	hdr.Gname = "staff"
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(hdr); err != nil {
+		t.Fatalf("WriteHeader: %v", err)
+	}
+	if _, err := io.Copy(tw, tr); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	withNames = buf.Bytes()
+
+	sumWithNames, err := tarSumHexFromReader(bytes.NewReader(withNames), Version1)
+	if err != nil {
+		t.Fatalf("tarSumHexFromReader: %v", err)
+	}
+	sumWithoutNames, err := tarSumHexFromReader(bytes.NewReader(withoutNames), Version1)
+	if err != nil {
+		t.Fatalf("tarSumHexFromReader: %v", err)
+	}
+	if sumWithNames != sumWithoutNames {
+		t.Errorf("TarSum digest depends on Uname/Gname, want them zeroed before hashing: %s != %s", sumWithNames, sumWithoutNames)
+	}
+}
+
+func TestNewVerifierRejectsGarbage(t *testing.T) {
+	if _, err := NewVerifier("not-a-digest"); err == nil {
+		t.Error("NewVerifier(garbage) = nil error, want error")
+	}
+}
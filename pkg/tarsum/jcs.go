@@ -0,0 +1,181 @@
+package tarsum
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// MarshalEntryJCS renders h as a canonical JSON document describing the
+// fields that determine a tar entry's identity: name, mode, ownership,
+// size, type flag, link target, device numbers, and xattrs
+// (base64-encoded). PAX records take
+// precedence over their ustar counterparts when present, the same way
+// v2TarHeaderSelect resolves them. Object keys are sorted lexicographically
+// and the output contains no insignificant whitespace, which is the subset
+// of RFC 8785 (JCS) relevant to reproducing this digest outside of Go.
+func MarshalEntryJCS(h *tar.Header) ([]byte, error) {
+	name := normalizeTarName(h.Name)
+	linkname := h.Linkname
+	size := h.Size
+	uid := h.Uid
+	gid := h.Gid
+	uname := h.Uname
+	gname := h.Gname
+
+	if v, ok := h.PAXRecords["path"]; ok {
+		name = normalizeTarName(v)
+	}
+	if v, ok := h.PAXRecords["linkpath"]; ok {
+		linkname = v
+	}
+	if v, ok := h.PAXRecords["size"]; ok {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			size = n
+		}
+	}
+	if v, ok := h.PAXRecords["uid"]; ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			uid = n
+		}
+	}
+	if v, ok := h.PAXRecords["gid"]; ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			gid = n
+		}
+	}
+	if v, ok := h.PAXRecords["uname"]; ok {
+		uname = v
+	}
+	if v, ok := h.PAXRecords["gname"]; ok {
+		gname = v
+	}
+
+	entry := map[string]any{
+		"name":     name,
+		"mode":     h.Mode,
+		"uid":      uid,
+		"gid":      gid,
+		"size":     size,
+		"typeflag": string([]byte{h.Typeflag}),
+		"linkname": linkname,
+		"uname":    uname,
+		"gname":    gname,
+		"devmajor": h.Devmajor,
+		"devminor": h.Devminor,
+	}
+
+	if xattrs := v2CollectXattrs(h); len(xattrs) > 0 {
+		encoded := make(map[string]string, len(xattrs))
+		for _, kv := range xattrs {
+			encoded[kv[0]] = base64.StdEncoding.EncodeToString([]byte(kv[1]))
+		}
+		entry["xattrs"] = encoded
+	}
+
+	// encoding/json marshals map[string]any keys in sorted order and emits
+	// no insignificant whitespace, which is what gives this its canonical
+	// form. json.Marshal itself would HTML-escape '<', '>', '&' and the
+	// U+2028/U+2029 line separators, which no other JSON implementation
+	// does by default -- that would make the digest irreproducible from
+	// Python/Rust/JS for any entry whose name, link target, or xattr value
+	// contains one of those characters, so go through an Encoder with
+	// SetEscapeHTML(false) instead and trim the trailing newline it adds.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(entry); err != nil {
+		return nil, err
+	}
+	// The Encoder still escapes U+2028 and U+2029 with SetEscapeHTML(false);
+	// write those two back as literal characters and copy every other escape
+	// sequence (an escaped backslash included) unchanged.
+	out := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+	res := make([]byte, 0, len(out))
+	for i := 0; i < len(out); i++ {
+		switch {
+		case bytes.HasPrefix(out[i:], []byte("\\u2028")), bytes.HasPrefix(out[i:], []byte("\\u2029")):
+			res = append(res, string(0x2020+rune(out[i+5]-'0'))...)
+			i += 5
+		case out[i] == '\\':
+			res, i = append(res, out[i], out[i+1]), i+1
+		default:
+			res = append(res, out[i])
+		}
+	}
+	return res, nil
+}
+
+// jcsDigestFromReader computes the hex-encoded sha256 of the
+// newline-separated canonical JSON documents for every entry in the
+// uncompressed tar stream r.
+func jcsDigestFromReader(r io.Reader) (string, error) {
+	h := sha256.New()
+	tr := tar.NewReader(r)
+	first := true
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		doc, err := MarshalEntryJCS(hdr)
+		if err != nil {
+			return "", err
+		}
+		if !first {
+			h.Write([]byte("\n"))
+		}
+		first = false
+		h.Write(doc)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// VerifyJCS computes the VersionJSON digest of the uncompressed tar stream
+// r and compares it against expected, which must be in the
+// "tarsum.json+sha256:<hex>" form.
+func VerifyJCS(r io.Reader, expected string) error {
+	sum, err := jcsDigestFromReader(r)
+	if err != nil {
+		return err
+	}
+	if got := VersionJSON.String() + "+sha256:" + sum; got != expected {
+		return fmt.Errorf("tarsum: digest mismatch: got %s, expected %s", got, expected)
+	}
+	return nil
+}
+
+// ConvertTarSumToJCS re-hashes a layer under VersionJSON, given its
+// original TarSum checksum (old) and its uncompressed tar bytes (r). It
+// first verifies r actually hashes to old, so a stale or mismatched layer
+// is rejected rather than silently migrated.
+func ConvertTarSumToJCS(old string, r io.Reader) (string, error) {
+	v, err := GetVersionFromTarsum(old)
+	if err != nil {
+		return "", fmt.Errorf("tarsum: %q is not a TarSum checksum: %w", old, err)
+	}
+
+	var buf bytes.Buffer
+	oldSum, err := tarSumHexFromReader(io.TeeReader(r, &buf), v)
+	if err != nil {
+		return "", err
+	}
+	if got := v.String() + "+sha256:" + oldSum; got != old {
+		return "", fmt.Errorf("tarsum: layer does not match %s", old)
+	}
+
+	newSum, err := jcsDigestFromReader(&buf)
+	if err != nil {
+		return "", err
+	}
+	return VersionJSON.String() + "+sha256:" + newSum, nil
+}
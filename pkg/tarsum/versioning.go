@@ -2,8 +2,10 @@ package tarsum
 
 import (
 	"archive/tar"
+	"encoding/base64"
 	"errors"
 	"io"
+	"path"
 	"sort"
 	"strconv"
 	"strings"
@@ -18,6 +20,16 @@ type Version int
 const (
 	Version0 Version = iota
 	Version1
+	// Version2 fixes known gaps in Version1: it also recognizes LIBARCHIVE.xattr.*
+	// PAX records, prefers PAX values over their ustar counterparts for the
+	// fields that affect file identity, and normalizes tar header names so
+	// that archives produced by different tar implementations hash the same.
+	Version2
+	// VersionJSON selects the canonical-JSON header encoding instead of the
+	// key+value concatenation used by the other versions, so that the sum
+	// can be reproduced by verifiers outside this package. See
+	// MarshalEntryJCS and VerifyJCS.
+	VersionJSON
 	// VersionDev this constant will be either the latest or an unsettled next-version of the TarSum calculation
 	VersionDev
 )
@@ -52,14 +64,18 @@ func GetVersions() []Version {
 
 var (
 	tarSumVersions = map[Version]string{
-		Version0:   "tarsum",
-		Version1:   "tarsum.v1",
-		VersionDev: "tarsum.dev",
+		Version0:    "tarsum",
+		Version1:    "tarsum.v1",
+		Version2:    "tarsum.v2",
+		VersionJSON: "tarsum.json",
+		VersionDev:  "tarsum.dev",
 	}
 	tarSumVersionsByName = map[string]Version{
-		"tarsum":     Version0,
-		"tarsum.v1":  Version1,
-		"tarsum.dev": VersionDev,
+		"tarsum":      Version0,
+		"tarsum.v1":   Version1,
+		"tarsum.v2":   Version2,
+		"tarsum.json": VersionJSON,
+		"tarsum.dev":  VersionDev,
 	}
 )
 
@@ -150,10 +166,121 @@ func v1TarHeaderSelect(h *tar.Header) (orderedHeaders [][2]string) {
 	return orderedHeaders
 }
 
+// paxIdentityFields maps the PAX records that affect file identity to the
+// index of the corresponding field in the slice returned by
+// v0TarHeaderSelect, so that the PAX value can be substituted for the
+// ustar value before the header is serialized.
+var paxIdentityFields = map[string]int{
+	"path":     0,
+	"uid":      2,
+	"gid":      3,
+	"size":     4,
+	"linkpath": 7,
+	"uname":    8,
+	"gname":    9,
+}
+
+const (
+	paxSchilyXattr     = "SCHILY.xattr."
+	paxLibarchiveXattr = "LIBARCHIVE.xattr."
+)
+
+func v2TarHeaderSelect(h *tar.Header) (orderedHeaders [][2]string) {
+	// Start from the v0 fields, then substitute in any PAX records that
+	// carry the canonical value for a field affecting file identity (long
+	// names, large uid/gid/size, etc. that the ustar header can't hold).
+	basic := v0TarHeaderSelect(h)
+	for key, idx := range paxIdentityFields {
+		if v, ok := h.PAXRecords[key]; ok {
+			basic[idx] = [2]string{basic[idx][0], v}
+		}
+	}
+	basic[0] = [2]string{"name", normalizeTarName(basic[0][1])}
+
+	xattrs := v2CollectXattrs(h)
+
+	// Make the slice with enough capacity to hold the 11 basic headers
+	// we want from the v0 selector plus however many xattrs we have.
+	orderedHeaders = make([][2]string, 0, 11+len(xattrs))
+
+	// Copy all headers from v0 excluding the 'mtime' header (the 5th element).
+	orderedHeaders = append(orderedHeaders, basic[0:5]...)
+	orderedHeaders = append(orderedHeaders, basic[6:]...)
+
+	// Finally, append the sorted xattrs.
+	orderedHeaders = append(orderedHeaders, xattrs...)
+
+	return orderedHeaders
+}
+
+// v2CollectXattrs gathers extended attributes from both the SCHILY.xattr.*
+// and LIBARCHIVE.xattr.* PAX namespaces (decoding the base64-encoded
+// LIBARCHIVE values to raw bytes), plus any legacy h.Xattrs entries. When
+// the same attribute appears under both namespaces, the SCHILY value wins,
+// matching archive/tar's own precedence for h.Xattrs over h.PAXRecords.
+func v2CollectXattrs(h *tar.Header) [][2]string {
+	merged := make(map[string]string)
+
+	for k, v := range h.PAXRecords {
+		if xattr, ok := strings.CutPrefix(k, paxLibarchiveXattr); ok {
+			if decoded, err := base64.StdEncoding.DecodeString(v); err == nil {
+				merged[xattr] = string(decoded)
+			}
+		}
+	}
+	for k, v := range h.PAXRecords {
+		if xattr, ok := strings.CutPrefix(k, paxSchilyXattr); ok {
+			merged[xattr] = v
+		}
+	}
+	for k, v := range h.Xattrs { //nolint:staticcheck // field deprecated in stdlib
+		merged[k] = v
+	}
+
+	xattrs := make([][2]string, 0, len(merged))
+	for k, v := range merged {
+		xattrs = append(xattrs, [2]string{k, v})
+	}
+	sort.Slice(xattrs, func(i, j int) bool { return xattrs[i][0] < xattrs[j][0] })
+	return xattrs
+}
+
+// normalizeTarName trims a leading "./" and cleans the rest with
+// path.Clean, which collapses redundant slashes, drops "." elements and
+// resolves ".." elements; a trailing slash is kept. This is so that archives written by different tar implementations
+// (archive/tar, go-winio's tar, libarchive-based bsdtar) describing the
+// same tree produce the same name, and therefore the same sum.
+func normalizeTarName(name string) string {
+	name = strings.TrimPrefix(name, "./")
+	if name == "" {
+		return name
+	}
+	hasTrailingSlash := strings.HasSuffix(name, "/")
+	cleaned := path.Clean(name)
+	if hasTrailingSlash && !strings.HasSuffix(cleaned, "/") {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// registeredHeaderSelectors holds the versions whose digest is computed by
+// tarSumHexFromReader: one hash per entry over its selected-header-bytes
+// plus content, then one hash over the sorted per-entry digests. VersionJSON is deliberately absent: its
+// digest (see jcsDigestFromReader) hashes one canonical JSON document per
+// entry, newline-separated, over header fields only -- a different shape
+// that tarSumHexFromReader cannot produce, so there is no headerSelectFunc
+// for it to reuse.
+//
+// This is an intentional deviation from registering VersionJSON here
+// outright: doing so briefly left getTarHeaderSelector(VersionJSON)
+// reachable and silently wrong, worked around only by a special case in
+// newTarSumVerifier. VerifyJCS and ConvertTarSumToJCS are VersionJSON's
+// only supported entry points.
 var registeredHeaderSelectors = map[Version]tarHeaderSelectFunc{
 	Version0:   v0TarHeaderSelect,
 	Version1:   v1TarHeaderSelect,
-	VersionDev: v1TarHeaderSelect,
+	Version2:   v2TarHeaderSelect,
+	VersionDev: v2TarHeaderSelect,
 }
 
 func getTarHeaderSelector(v Version) (tarHeaderSelector, error) {
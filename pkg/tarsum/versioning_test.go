@@ -0,0 +1,137 @@
+package tarsum
+
+import (
+	"archive/tar"
+	"encoding/base64"
+	"testing"
+)
+
+// headerString renders the ordered headers the way WriteV1Header would
+// serialize them, so vectors below can be compared as a single string.
+func headerString(orderedHeaders [][2]string) string {
+	var out string
+	for _, elem := range orderedHeaders {
+		out += elem[0] + elem[1]
+	}
+	return out
+}
+
+func TestV2TarHeaderSelectNameNormalization(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"leading dot-slash", "./foo/bar", "foo/bar"},
+		{"redundant slashes", "foo//bar///baz", "foo/bar/baz"},
+		{"trailing slash preserved", "./foo/bar/", "foo/bar/"},
+		{"already clean", "foo/bar", "foo/bar"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := normalizeTarName(tc.in)
+			if got != tc.want {
+				t.Errorf("normalizeTarName(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestV2TarHeaderSelectXattrNamespaces(t *testing.T) {
+	h := &tar.Header{
+		Name: "foo",
+		PAXRecords: map[string]string{
+			"SCHILY.xattr.user.a":     "schily-value",
+			"LIBARCHIVE.xattr.user.a": base64.StdEncoding.EncodeToString([]byte("libarchive-value")),
+			"LIBARCHIVE.xattr.user.b": base64.StdEncoding.EncodeToString([]byte("libarchive-only")),
+		},
+	}
+
+	xattrs := v2CollectXattrs(h)
+	got := map[string]string{}
+	for _, kv := range xattrs {
+		got[kv[0]] = kv[1]
+	}
+
+	if got["user.a"] != "schily-value" {
+		t.Errorf("SCHILY value should win on conflict, got %q", got["user.a"])
+	}
+	if got["user.b"] != "libarchive-only" {
+		t.Errorf("LIBARCHIVE-only xattr should be decoded, got %q", got["user.b"])
+	}
+}
+
+func TestV2TarHeaderSelectPAXIdentityOverride(t *testing.T) {
+	h := &tar.Header{
+		Name: "short",
+		Uid:  0,
+		Size: 1,
+		PAXRecords: map[string]string{
+			"path": "a/very/long/path/that/does/not/fit/in/the/ustar/name/field",
+			"uid":  "100000",
+			"size": "4294967296",
+		},
+	}
+
+	orderedHeaders := v2TarHeaderSelect(h)
+	got := map[string]string{}
+	for _, kv := range orderedHeaders {
+		got[kv[0]] = kv[1]
+	}
+
+	if got["name"] != "a/very/long/path/that/does/not/fit/in/the/ustar/name/field" {
+		t.Errorf("expected PAX path to override ustar name, got %q", got["name"])
+	}
+	if got["uid"] != "100000" {
+		t.Errorf("expected PAX uid to override ustar uid, got %q", got["uid"])
+	}
+	if got["size"] != "4294967296" {
+		t.Errorf("expected PAX size to override ustar size, got %q", got["size"])
+	}
+}
+
+// TestV2TarHeaderSelectCrossImplementation locks in that headers for the
+// same logical file, as emitted by different tar writers, select to the
+// same ordered headers under Version2.
+func TestV2TarHeaderSelectCrossImplementation(t *testing.T) {
+	const xattrVal = "en_US.UTF-8"
+
+	stdlib := &tar.Header{
+		Name:   "etc/locale.conf",
+		Uid:    0,
+		Gid:    0,
+		Size:   11,
+		Xattrs: map[string]string{"user.charset": xattrVal}, //nolint:staticcheck // field deprecated in stdlib
+	}
+
+	// go-winio's backuptar writes xattrs under SCHILY.xattr.* PAX records
+	// rather than the deprecated h.Xattrs field.
+	winio := &tar.Header{
+		Name: "./etc/locale.conf",
+		Uid:  0,
+		Gid:  0,
+		Size: 11,
+		PAXRecords: map[string]string{
+			"SCHILY.xattr.user.charset": xattrVal,
+		},
+	}
+
+	// bsdtar (libarchive) writes xattrs under LIBARCHIVE.xattr.*, base64
+	// encoded, and may emit doubled path separators.
+	bsdtar := &tar.Header{
+		Name: "etc//locale.conf",
+		Uid:  0,
+		Gid:  0,
+		Size: 11,
+		PAXRecords: map[string]string{
+			"LIBARCHIVE.xattr.user.charset": base64.StdEncoding.EncodeToString([]byte(xattrVal)),
+		},
+	}
+
+	want := headerString(v2TarHeaderSelect(stdlib))
+	for name, h := range map[string]*tar.Header{"winio": winio, "bsdtar": bsdtar} {
+		if got := headerString(v2TarHeaderSelect(h)); got != want {
+			t.Errorf("%s vector: v2TarHeaderSelect = %q, want %q (stdlib)", name, got, want)
+		}
+	}
+}